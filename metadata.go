@@ -0,0 +1,34 @@
+package shoutcast
+
+import "regexp"
+
+// streamTitlePattern extracts the StreamTitle field out of a raw ICY
+// metadata block, e.g. "StreamTitle='Artist - Song';StreamUrl='...';".
+var streamTitlePattern = regexp.MustCompile(`StreamTitle='([^']*)';`)
+
+// Metadata represents the metadata associated with a stream at a point in
+// time.
+type Metadata struct {
+	// The current StreamTitle, however it was obtained: parsed from an
+	// in-band ICY/Ogg metadata block, or synthesized by a MetadataSource
+	// or playlist entry.
+	StreamTitle string
+}
+
+// NewMetadata parses a raw ICY metadata block into a Metadata.
+func NewMetadata(raw []byte) *Metadata {
+	m := &Metadata{}
+	if match := streamTitlePattern.FindSubmatch(raw); match != nil {
+		m.StreamTitle = string(match[1])
+	}
+	return m
+}
+
+// Equals reports whether m and other carry the same metadata. A nil
+// receiver or argument is only equal to another nil.
+func (m *Metadata) Equals(other *Metadata) bool {
+	if m == nil || other == nil {
+		return m == other
+	}
+	return m.StreamTitle == other.StreamTitle
+}
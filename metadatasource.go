@@ -0,0 +1,169 @@
+package shoutcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultPollInterval is used by JSONPollSource when neither the response
+// nor FallbackInterval supply one.
+const defaultPollInterval = 30 * time.Second
+
+// MetadataSource is an out-of-band source of now-playing metadata, polled
+// independently of whatever metadata a stream's own bytes carry. It lets a
+// Stream pick up titles from a station's now-playing API when its ICY
+// StreamTitle is empty or stale.
+type MetadataSource interface {
+	// Next blocks until metadata is available or ctx is done, returning
+	// it along with how long the caller should wait before calling Next
+	// again.
+	Next(ctx context.Context) (*Metadata, time.Duration, error)
+}
+
+// AttachMetadataSource spawns a goroutine that polls src in a loop and
+// feeds whatever it returns into s.MetadataCallbackFunc, the same callback
+// in-band stream metadata uses. Values unchanged from the previous call
+// (per Metadata.Equals) are ignored. The goroutine exits once ctx is done.
+func (s *Stream) AttachMetadataSource(ctx context.Context, src MetadataSource) {
+	go func() {
+		var last *Metadata
+		for {
+			m, wait, err := src.Next(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Print("[WARN] metadata source error: ", err)
+			} else if m != nil && !m.Equals(last) {
+				last = m
+				if s.MetadataCallbackFunc != nil {
+					s.MetadataCallbackFunc(m)
+				}
+			}
+
+			if wait <= 0 {
+				wait = defaultPollInterval
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// JSONPollSource is a MetadataSource that periodically fetches JSON from a
+// now-playing API and extracts a title (and optional artist) from it.
+type JSONPollSource struct {
+	// URL to poll for now-playing metadata.
+	URL string
+
+	// TitlePath and ArtistPath are dot-separated paths into the decoded
+	// JSON, e.g. "now_playing.song.title". TitlePath defaults to
+	// "title"; ArtistPath is optional.
+	TitlePath  string
+	ArtistPath string
+
+	// PollIntervalPath is a dot-separated path to a response-driven poll
+	// interval in seconds, mirroring the polling_timeout field used by
+	// BBC's nhppolling endpoint. If empty, or absent from a given
+	// response, FallbackInterval is used instead.
+	PollIntervalPath string
+
+	// FallbackInterval is used when the response carries no poll
+	// interval of its own. Defaults to 30s.
+	FallbackInterval time.Duration
+
+	// Client is the http.Client used to fetch URL. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// Next implements MetadataSource.
+func (j *JSONPollSource) Next(ctx context.Context) (*Metadata, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", j.URL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, j.fallbackInterval(), err
+	}
+	defer resp.Body.Close()
+
+	var doc interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, j.fallbackInterval(), fmt.Errorf("cannot decode metadata from %s: %v", j.URL, err)
+	}
+
+	title, _ := lookupJSONPath(doc, j.titlePath()).(string)
+	artist, _ := lookupJSONPath(doc, j.ArtistPath).(string)
+
+	streamTitle := title
+	if artist != "" && title != "" {
+		streamTitle = fmt.Sprintf("%s - %s", artist, title)
+	}
+
+	return &Metadata{StreamTitle: streamTitle}, j.pollInterval(doc), nil
+}
+
+func (j *JSONPollSource) titlePath() string {
+	if j.TitlePath != "" {
+		return j.TitlePath
+	}
+	return "title"
+}
+
+func (j *JSONPollSource) fallbackInterval() time.Duration {
+	if j.FallbackInterval > 0 {
+		return j.FallbackInterval
+	}
+	return defaultPollInterval
+}
+
+// pollInterval reads the response-driven poll interval out of doc, falling
+// back to fallbackInterval when PollIntervalPath is unset or missing.
+func (j *JSONPollSource) pollInterval(doc interface{}) time.Duration {
+	if j.PollIntervalPath == "" {
+		return j.fallbackInterval()
+	}
+	secs, ok := lookupJSONPath(doc, j.PollIntervalPath).(float64)
+	if !ok || secs <= 0 {
+		return j.fallbackInterval()
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// lookupJSONPath walks a dot-separated path (e.g. "a.b.c") through a value
+// decoded from JSON, returning nil if any segment is missing or not an
+// object.
+func lookupJSONPath(v interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+
+	cur := v
+	for _, seg := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur, ok = m[seg]
+		if !ok {
+			return nil
+		}
+	}
+	return cur
+}
@@ -0,0 +1,141 @@
+package shoutcast
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"now_playing": map[string]interface{}{
+			"song": map[string]interface{}{
+				"title": "Song Title",
+			},
+		},
+		"polling_timeout": float64(15),
+	}
+
+	if got := lookupJSONPath(doc, "now_playing.song.title"); got != "Song Title" {
+		t.Fatalf("lookupJSONPath(title) = %v, want %q", got, "Song Title")
+	}
+	if got := lookupJSONPath(doc, "polling_timeout"); got != float64(15) {
+		t.Fatalf("lookupJSONPath(polling_timeout) = %v, want 15", got)
+	}
+	if got := lookupJSONPath(doc, "missing.path"); got != nil {
+		t.Fatalf("lookupJSONPath(missing) = %v, want nil", got)
+	}
+}
+
+func TestJSONPollSourceNext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"title":"Song","artist":"Artist","polling_timeout":5}`))
+	}))
+	defer server.Close()
+
+	src := &JSONPollSource{
+		URL:              server.URL,
+		ArtistPath:       "artist",
+		PollIntervalPath: "polling_timeout",
+		FallbackInterval: time.Minute,
+	}
+
+	m, wait, err := src.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if m.StreamTitle != "Artist - Song" {
+		t.Fatalf("StreamTitle = %q, want %q", m.StreamTitle, "Artist - Song")
+	}
+	if wait != 5*time.Second {
+		t.Fatalf("wait = %v, want 5s", wait)
+	}
+}
+
+// fakeMetadataSource replays a fixed script of Next responses, then blocks
+// on ctx so tests can observe AttachMetadataSource's goroutine exiting
+// cleanly once the context is canceled.
+type fakeMetadataSource struct {
+	responses []fakeMetadataResponse
+
+	mu    sync.Mutex
+	calls int
+}
+
+type fakeMetadataResponse struct {
+	metadata *Metadata
+	wait     time.Duration
+}
+
+func (f *fakeMetadataSource) Next(ctx context.Context) (*Metadata, time.Duration, error) {
+	f.mu.Lock()
+	i := f.calls
+	f.calls++
+	f.mu.Unlock()
+
+	if i < len(f.responses) {
+		r := f.responses[i]
+		return r.metadata, r.wait, nil
+	}
+	<-ctx.Done()
+	return nil, 0, ctx.Err()
+}
+
+func (f *fakeMetadataSource) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestAttachMetadataSource(t *testing.T) {
+	metaA := &Metadata{StreamTitle: "A"}
+	metaB := &Metadata{StreamTitle: "B"}
+	src := &fakeMetadataSource{responses: []fakeMetadataResponse{
+		{metadata: metaA, wait: time.Millisecond},
+		{metadata: metaA, wait: time.Millisecond}, // duplicate of the previous value, must be deduped
+		{metadata: metaB, wait: time.Millisecond},
+	}}
+
+	var mu sync.Mutex
+	var got []*Metadata
+	s := &Stream{MetadataCallbackFunc: func(m *Metadata) {
+		mu.Lock()
+		got = append(got, m)
+		mu.Unlock()
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.AttachMetadataSource(ctx, src)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for metadata callbacks")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	if len(got) != 2 || got[0].StreamTitle != "A" || got[1].StreamTitle != "B" {
+		t.Fatalf("callbacks = %+v, want [A B] (duplicate A must be deduped)", got)
+	}
+	mu.Unlock()
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	calls := src.callCount()
+	time.Sleep(20 * time.Millisecond)
+	if src.callCount() != calls {
+		t.Fatal("AttachMetadataSource's goroutine kept polling after ctx was canceled")
+	}
+}
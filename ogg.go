@@ -0,0 +1,311 @@
+package shoutcast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// isOggContentType reports whether ct indicates an Ogg-contained stream
+// (Vorbis, Opus or FLAC-in-Ogg), the case where metadata travels as
+// in-band Vorbis comment packets rather than ICY metaint blocks.
+func isOggContentType(ct string) bool {
+	ct = strings.ToLower(strings.TrimSpace(strings.SplitN(ct, ";", 2)[0]))
+	return ct == "application/ogg" || ct == "audio/ogg"
+}
+
+// oggCapturePattern is the 4-byte magic that starts every Ogg page.
+const oggCapturePattern = "OggS"
+
+const (
+	oggContinuedFlag = 0x01
+	oggBOSFlag       = 0x02
+)
+
+// oggReader wraps an Ogg bitstream, passing every byte through to Read
+// unchanged while parsing page and packet boundaries on the side in order
+// to surface comment metadata (TITLE=/ARTIST=/ALBUM=) via the
+// metadataProvider interface Stream.Read already knows how to poll. It
+// understands the Vorbis, Opus and FLAC-in-Ogg comment mappings.
+type oggReader struct {
+	rc  io.ReadCloser
+	buf bytes.Buffer // bytes read but not yet parsed into a full page
+
+	// State for the logical stream currently being parsed. A new
+	// bitstream serial number (a chained stream) resets all of this.
+	serial     uint32
+	haveSerial bool
+	packet     []byte
+	packetIdx  int
+
+	// codec identified from the first packet of the logical stream, and
+	// (FLAC only) whether the native FLAC metadata blocks that follow
+	// have finished (the last one is marked with a "this is the last
+	// block" flag, after which audio frames start).
+	codec          oggCodec
+	flacHeaderDone bool
+
+	pending *Metadata
+}
+
+// oggCodec identifies which mapping a logical stream's packets follow,
+// since Vorbis/Opus and FLAC-in-Ogg carry their comment header very
+// differently.
+type oggCodec int
+
+const (
+	oggCodecUnknown oggCodec = iota
+	oggCodecVorbis
+	oggCodecOpus
+	oggCodecFLAC
+)
+
+// flacVorbisCommentBlockType is the FLAC metadata block type (the low 7
+// bits of a block header's first byte) that holds a Vorbis-comment-layout
+// tag list.
+const flacVorbisCommentBlockType = 4
+
+// newOggReader wraps rc, parsing Ogg pages as they're read through.
+func newOggReader(rc io.ReadCloser) *oggReader {
+	return &oggReader{rc: rc}
+}
+
+// Read implements io.Reader, returning the underlying bytes unmodified.
+func (o *oggReader) Read(p []byte) (int, error) {
+	n, err := o.rc.Read(p)
+	if n > 0 {
+		o.buf.Write(p[:n])
+		o.parsePages()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (o *oggReader) Close() error {
+	return o.rc.Close()
+}
+
+// nextMetadata implements metadataProvider. It returns the metadata
+// synthesized from the most recently parsed comment header, clearing it so
+// it is only delivered once.
+func (o *oggReader) nextMetadata() *Metadata {
+	m := o.pending
+	o.pending = nil
+	return m
+}
+
+// parsePages consumes as many complete Ogg pages as are currently
+// buffered, feeding their packets to handlePacket as they complete.
+func (o *oggReader) parsePages() {
+	for {
+		data := o.buf.Bytes()
+		if len(data) < 27 {
+			return
+		}
+		if !bytes.HasPrefix(data, []byte(oggCapturePattern)) {
+			// Not aligned on a page boundary. This shouldn't happen on a
+			// well-formed stream; resync a byte at a time rather than
+			// get stuck waiting for a page that will never parse.
+			o.buf.Next(1)
+			continue
+		}
+
+		headerType := data[5]
+		serial := binary.LittleEndian.Uint32(data[14:18])
+		segments := int(data[26])
+		if len(data) < 27+segments {
+			return
+		}
+
+		segTable := data[27 : 27+segments]
+		payloadLen := 0
+		for _, seg := range segTable {
+			payloadLen += int(seg)
+		}
+
+		pageLen := 27 + segments + payloadLen
+		if len(data) < pageLen {
+			return
+		}
+
+		o.handlePage(serial, headerType, segTable, data[27+segments:pageLen])
+		o.buf.Next(pageLen)
+	}
+}
+
+// handlePage reassembles the packets carried by one page, resetting the
+// in-progress packet on a new serial number (a chained stream) or a BOS
+// page, and handing each packet to handlePacket as soon as its segment
+// table marks it complete.
+func (o *oggReader) handlePage(serial uint32, headerType byte, segTable, payload []byte) {
+	if !o.haveSerial || serial != o.serial {
+		o.serial = serial
+		o.haveSerial = true
+		o.packet = nil
+		o.packetIdx = 0
+		o.codec = oggCodecUnknown
+		o.flacHeaderDone = false
+	}
+	if headerType&oggBOSFlag != 0 {
+		o.packet = nil
+		o.packetIdx = 0
+		o.codec = oggCodecUnknown
+		o.flacHeaderDone = false
+	}
+	if headerType&oggContinuedFlag == 0 {
+		o.packet = o.packet[:0]
+	}
+
+	off := 0
+	for _, seg := range segTable {
+		o.packet = append(o.packet, payload[off:off+int(seg)]...)
+		off += int(seg)
+
+		if seg < 255 {
+			o.handlePacket(o.packet)
+			o.packetIdx++
+			o.packet = nil
+		}
+		// seg == 255 means the packet continues into the next segment,
+		// on this page or the next one; leave o.packet accumulating.
+	}
+}
+
+// handlePacket inspects one complete packet, dispatching on the codec
+// identified from the logical stream's first (identification header)
+// packet.
+func (o *oggReader) handlePacket(packet []byte) {
+	if o.packetIdx == 0 {
+		o.codec = detectOggCodec(packet)
+		if o.codec == oggCodecUnknown {
+			log.Print("[WARN] Ogg logical stream uses an unrecognized codec; no metadata will be parsed for it")
+		}
+		return
+	}
+
+	switch o.codec {
+	case oggCodecFLAC:
+		o.handleFLACMetadataPacket(packet)
+	case oggCodecVorbis, oggCodecOpus:
+		if o.packetIdx == 1 {
+			o.handleCommentHeaderPacket(packet)
+		}
+	}
+}
+
+// detectOggCodec identifies a logical stream's codec from its first
+// packet (the identification header).
+func detectOggCodec(packet []byte) oggCodec {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")):
+		return oggCodecVorbis
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		return oggCodecOpus
+	case len(packet) >= 9 && packet[0] == 0x7F && string(packet[1:5]) == "FLAC":
+		return oggCodecFLAC
+	}
+	return oggCodecUnknown
+}
+
+// handleCommentHeaderPacket parses the Vorbis/Opus comment header packet:
+// its magic prefix followed directly by the vendor-string-plus-comment-list
+// layout parseVorbisComments understands.
+func (o *oggReader) handleCommentHeaderPacket(packet []byte) {
+	var prefix string
+	switch o.codec {
+	case oggCodecVorbis:
+		prefix = "\x03vorbis"
+	case oggCodecOpus:
+		prefix = "OpusTags"
+	}
+	if !bytes.HasPrefix(packet, []byte(prefix)) {
+		return
+	}
+
+	o.emitComments(parseVorbisComments(packet[len(prefix):]))
+}
+
+// handleFLACMetadataPacket parses one native FLAC metadata block: a
+// packet-sized byte (top bit: this is the last metadata block; low 7
+// bits: block type) plus a 24-bit big-endian length, present in every
+// packet of a FLAC-in-Ogg logical stream up to (and including) the one
+// marked last, after which audio frames start. Only the VORBIS_COMMENT
+// block (type 4) carries tags.
+func (o *oggReader) handleFLACMetadataPacket(packet []byte) {
+	if o.flacHeaderDone || len(packet) < 4 {
+		return
+	}
+
+	last := packet[0]&0x80 != 0
+	blockType := packet[0] & 0x7F
+	length := int(packet[1])<<16 | int(packet[2])<<8 | int(packet[3])
+
+	if blockType == flacVorbisCommentBlockType && 4+length <= len(packet) {
+		o.emitComments(parseVorbisComments(packet[4 : 4+length]))
+	}
+
+	if last {
+		o.flacHeaderDone = true
+	}
+}
+
+// emitComments synthesizes an ICY-equivalent "artist - title" Metadata
+// from decoded Vorbis-comment fields, if a title or artist was present.
+func (o *oggReader) emitComments(fields map[string]string) {
+	title, artist := fields["title"], fields["artist"]
+	if title == "" && artist == "" {
+		return
+	}
+
+	streamTitle := title
+	if artist != "" {
+		streamTitle = fmt.Sprintf("%s - %s", artist, title)
+	}
+	o.pending = &Metadata{StreamTitle: streamTitle}
+}
+
+// parseVorbisComments decodes the comment layout shared by Vorbis and
+// Opus: a length-prefixed vendor string followed by a length-prefixed list
+// of "KEY=value" entries. Keys are returned lowercased.
+func parseVorbisComments(data []byte) map[string]string {
+	fields := map[string]string{}
+
+	readUint32 := func() (uint32, bool) {
+		if len(data) < 4 {
+			return 0, false
+		}
+		v := binary.LittleEndian.Uint32(data[:4])
+		data = data[4:]
+		return v, true
+	}
+
+	vendorLen, ok := readUint32()
+	if !ok || uint64(vendorLen) > uint64(len(data)) {
+		return fields
+	}
+	data = data[vendorLen:]
+
+	count, ok := readUint32()
+	if !ok {
+		return fields
+	}
+
+	for i := uint32(0); i < count; i++ {
+		entryLen, ok := readUint32()
+		if !ok || uint64(entryLen) > uint64(len(data)) {
+			break
+		}
+		entry := string(data[:entryLen])
+		data = data[entryLen:]
+
+		eq := strings.IndexByte(entry, '=')
+		if eq < 0 {
+			continue
+		}
+		fields[strings.ToLower(entry[:eq])] = entry[eq+1:]
+	}
+	return fields
+}
@@ -0,0 +1,126 @@
+package shoutcast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// encodeVorbisComments builds the vendor+comment-list payload
+// parseVorbisComments expects.
+func encodeVorbisComments(vendor string, tags map[string]string) []byte {
+	var buf []byte
+
+	put32 := func(v uint32) {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], v)
+		buf = append(buf, b[:]...)
+	}
+
+	put32(uint32(len(vendor)))
+	buf = append(buf, vendor...)
+	put32(uint32(len(tags)))
+	for k, v := range tags {
+		entry := k + "=" + v
+		put32(uint32(len(entry)))
+		buf = append(buf, entry...)
+	}
+	return buf
+}
+
+// oggPage builds a single Ogg page carrying exactly one (non-continued,
+// <255 byte) packet, which is all these tests need.
+func oggPage(serial, seq uint32, headerType byte, packet []byte) []byte {
+	if len(packet) >= 255 {
+		panic("test packet too large for a single lacing value")
+	}
+
+	page := make([]byte, 0, 27+1+len(packet))
+	page = append(page, []byte(oggCapturePattern)...)
+	page = append(page, 0)                  // version
+	page = append(page, headerType)         // header_type
+	page = append(page, make([]byte, 8)...) // granule_position
+
+	var serialBuf, seqBuf [4]byte
+	binary.LittleEndian.PutUint32(serialBuf[:], serial)
+	binary.LittleEndian.PutUint32(seqBuf[:], seq)
+	page = append(page, serialBuf[:]...)
+	page = append(page, seqBuf[:]...)
+	page = append(page, make([]byte, 4)...) // checksum, unchecked by oggReader
+	page = append(page, 1)                  // page_segments
+	page = append(page, byte(len(packet)))  // segment_table
+	page = append(page, packet...)
+	return page
+}
+
+type staticReadCloser struct{ io.Reader }
+
+func (staticReadCloser) Close() error { return nil }
+
+func drain(t *testing.T, r *oggReader) *Metadata {
+	t.Helper()
+	buf := make([]byte, 64)
+	var last *Metadata
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if m := r.nextMetadata(); m != nil {
+				last = m
+			}
+		}
+		if err == io.EOF {
+			return last
+		}
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+}
+
+func TestOggReaderParsesVorbisComments(t *testing.T) {
+	identPacket := append([]byte("\x01vorbis"), make([]byte, 4)...)
+	commentPacket := append([]byte("\x03vorbis"), encodeVorbisComments("test", map[string]string{
+		"TITLE":  "Song",
+		"ARTIST": "Artist",
+	})...)
+
+	var data []byte
+	data = append(data, oggPage(1, 0, oggBOSFlag, identPacket)...)
+	data = append(data, oggPage(1, 1, 0, commentPacket)...)
+
+	r := newOggReader(staticReadCloser{bytes.NewReader(data)})
+	m := drain(t, r)
+	if m == nil || m.StreamTitle != "Artist - Song" {
+		t.Fatalf("metadata = %+v, want StreamTitle=\"Artist - Song\"", m)
+	}
+}
+
+func TestOggReaderParsesFLACComments(t *testing.T) {
+	identPacket := append([]byte{0x7F, 'F', 'L', 'A', 'C', 1, 0, 0, 2}, make([]byte, 4)...)
+
+	comment := encodeVorbisComments("test", map[string]string{"TITLE": "FLAC Song"})
+	metadataBlock := append([]byte{
+		0x80 | flacVorbisCommentBlockType,
+		byte(len(comment) >> 16), byte(len(comment) >> 8), byte(len(comment)),
+	}, comment...)
+
+	var data []byte
+	data = append(data, oggPage(2, 0, oggBOSFlag, identPacket)...)
+	data = append(data, oggPage(2, 1, 0, metadataBlock)...)
+
+	r := newOggReader(staticReadCloser{bytes.NewReader(data)})
+	m := drain(t, r)
+	if m == nil || m.StreamTitle != "FLAC Song" {
+		t.Fatalf("metadata = %+v, want StreamTitle=\"FLAC Song\"", m)
+	}
+}
+
+func TestIsOggContentType(t *testing.T) {
+	if !isOggContentType("application/ogg") || !isOggContentType("audio/ogg; charset=utf-8") {
+		t.Fatal("expected Ogg content types to be recognized")
+	}
+	if isOggContentType("audio/mpeg") {
+		t.Fatal("did not expect audio/mpeg to be recognized as Ogg")
+	}
+}
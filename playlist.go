@@ -0,0 +1,283 @@
+package shoutcast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPlaylistDepth guards against a playlist that (directly, or through a
+// chain of redirects) ends up referencing itself.
+const maxPlaylistDepth = 5
+
+// playlistEntry is one playable item parsed out of an M3U/M3U8/PLS
+// playlist, along with whatever title the playlist supplied for it.
+type playlistEntry struct {
+	url   string
+	title string
+}
+
+// playlistContentTypes maps the Content-Type values real-world playlist
+// responses use to the parser that understands their body format.
+var playlistContentTypes = map[string]func([]byte) []playlistEntry{
+	"application/vnd.apple.mpegurl": parseM3U,
+	"application/x-mpegurl":         parseM3U,
+	"audio/x-mpegurl":               parseM3U,
+	"audio/mpegurl":                 parseM3U,
+	"audio/x-scpls":                 parsePLS,
+	"application/pls+xml":           parsePLS,
+}
+
+// playlistParserFor returns the parser to use for a response, preferring
+// its Content-Type and falling back to the URL's file extension for
+// servers that serve playlists with a generic content type.
+func playlistParserFor(contentType, rawURL string) func([]byte) []playlistEntry {
+	ct := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	if p, ok := playlistContentTypes[strings.ToLower(ct)]; ok {
+		return p
+	}
+
+	lower := strings.ToLower(rawURL)
+	switch {
+	case strings.HasSuffix(lower, ".m3u8"), strings.HasSuffix(lower, ".m3u"):
+		return parseM3U
+	case strings.HasSuffix(lower, ".pls"):
+		return parsePLS
+	}
+	return nil
+}
+
+// hlsContentTypes are the Content-Type values that mark a response as
+// (possibly) HLS, as opposed to a plain M3U/M3U8 webradio playlist served
+// under the same generic mpegurl types.
+var hlsContentTypes = map[string]bool{
+	"application/vnd.apple.mpegurl": true,
+	"application/x-mpegurl":         true,
+	"audio/x-mpegurl":               true,
+	"audio/mpegurl":                 true,
+}
+
+// hlsMarkers are tags that only ever appear in an HLS media playlist,
+// never in an HLS master playlist, a plain webradio M3U/M3U8 (a single
+// station entry, or a list of alternate station URLs) or a PLS playlist.
+var hlsMarkers = [][]byte{
+	[]byte("#EXT-X-TARGETDURATION"),
+	[]byte("#EXT-X-MEDIA-SEQUENCE"),
+}
+
+// isHLSMediaPlaylist reports whether body is an HLS media playlist (a
+// directly playable list of media segments) as opposed to a master
+// playlist, or an unrelated M3U/M3U8 playlist that merely happens to use
+// #EXTINF the way every extended M3U does. It requires both an
+// HLS-flavored Content-Type or ".m3u8" URL suffix and at least one
+// HLS-specific tag in the body; #EXTINF alone is not enough; a plain
+// single-entry webradio .m3u8 has it too and must keep going through the
+// ordinary Open path so its ICY metadata still gets parsed.
+func isHLSMediaPlaylist(contentType, rawURL string, body []byte) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	looksLikeM3U8 := hlsContentTypes[ct] || strings.HasSuffix(strings.ToLower(rawURL), ".m3u8")
+	if !looksLikeM3U8 {
+		return false
+	}
+
+	for _, marker := range hlsMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseM3U parses plain M3U as well as extended M3U8 playlists, including
+// HLS master and media playlists: any non-comment line is an entry. Its
+// title comes from the last "#EXTINF:<duration>,<title>" line since the
+// previous entry, falling back to the last
+// "#EXT-X-PROGRAM-DATE-TIME:<timestamp>" line since the previous entry
+// when no EXTINF title was given. Other tags in between are skipped.
+func parseM3U(body []byte) []playlistEntry {
+	var entries []playlistEntry
+	var title, programDateTime string
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			if idx := strings.Index(line, ","); idx >= 0 {
+				title = strings.TrimSpace(line[idx+1:])
+			}
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			programDateTime = strings.TrimSpace(strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"))
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			entryTitle := title
+			if entryTitle == "" {
+				entryTitle = programDateTime
+			}
+			entries = append(entries, playlistEntry{url: line, title: entryTitle})
+			title, programDateTime = "", ""
+		}
+	}
+	return entries
+}
+
+// plsKeyPattern matches the "FileN", "TitleN" and "LengthN" keys used by
+// PLS playlists.
+var plsKeyPattern = regexp.MustCompile(`(?i)^(file|title|length)(\d+)$`)
+
+// parsePLS parses the "FileN=", "TitleN=" key/value format used by .pls
+// playlists.
+func parsePLS(body []byte) []playlistEntry {
+	files := map[int]string{}
+	titles := map[int]string{}
+	max := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		kind, idx, val, ok := parsePLSEntry(strings.TrimSpace(scanner.Text()))
+		if !ok {
+			continue
+		}
+		switch kind {
+		case "file":
+			files[idx] = val
+			if idx > max {
+				max = idx
+			}
+		case "title":
+			titles[idx] = val
+		}
+	}
+
+	entries := make([]playlistEntry, 0, len(files))
+	for i := 1; i <= max; i++ {
+		f, ok := files[i]
+		if !ok {
+			continue
+		}
+		entries = append(entries, playlistEntry{url: f, title: titles[i]})
+	}
+	return entries
+}
+
+// parsePLSEntry splits a "Key3=value" PLS line into its lowercased key
+// kind, its 1-based index and its value.
+func parsePLSEntry(line string) (kind string, idx int, val string, ok bool) {
+	eq := strings.Index(line, "=")
+	if eq < 0 {
+		return "", 0, "", false
+	}
+
+	m := plsKeyPattern.FindStringSubmatch(line[:eq])
+	if m == nil {
+		return "", 0, "", false
+	}
+
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, "", false
+	}
+	return strings.ToLower(m[1]), n, line[eq+1:], true
+}
+
+// resolvePlaylistURL resolves a (possibly relative) playlist entry against
+// the URL the playlist itself was fetched from.
+func resolvePlaylistURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
+// isPlayableURL reports whether u is something Open can plausibly dial.
+func isPlayableURL(u string) bool {
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+// hlsReader presents a sequence of HLS media segments, fetched over HTTP
+// one at a time, as a single continuous audio stream. It implements
+// metadataProvider so Stream.Read can surface a title synthesized from
+// each segment's EXTINF entry without any in-band metaint framing.
+type hlsReader struct {
+	ctx     context.Context
+	base    string
+	entries []playlistEntry
+	idx     int
+	cur     io.ReadCloser
+	pending *Metadata
+}
+
+// newHLSReader creates an hlsReader that serves entries in order,
+// resolving each one against base and fetching each segment under ctx.
+func newHLSReader(ctx context.Context, base string, entries []playlistEntry) *hlsReader {
+	return &hlsReader{ctx: ctx, base: base, entries: entries}
+}
+
+// Read implements io.Reader, transparently advancing to the next segment
+// as each one is exhausted.
+func (h *hlsReader) Read(p []byte) (int, error) {
+	for {
+		if h.cur == nil {
+			if h.idx >= len(h.entries) {
+				return 0, io.EOF
+			}
+			entry := h.entries[h.idx]
+			h.idx++
+
+			target, err := resolvePlaylistURL(h.base, entry.url)
+			if err != nil {
+				return 0, err
+			}
+			resp, err := fetchStream(h.ctx, target)
+			if err != nil {
+				return 0, fmt.Errorf("cannot fetch HLS segment %s: %v", target, err)
+			}
+			h.cur = resp.Body
+			if entry.title != "" {
+				h.pending = &Metadata{StreamTitle: entry.title}
+			}
+		}
+
+		n, err := h.cur.Read(p)
+		if err == io.EOF {
+			h.cur.Close()
+			h.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close implements io.Closer, closing the currently open segment, if any.
+func (h *hlsReader) Close() error {
+	if h.cur == nil {
+		return nil
+	}
+	return h.cur.Close()
+}
+
+// nextMetadata implements metadataProvider. It returns the title
+// synthesized for the segment most recently started, clearing it so it is
+// only delivered once.
+func (h *hlsReader) nextMetadata() *Metadata {
+	m := h.pending
+	h.pending = nil
+	return m
+}
@@ -0,0 +1,68 @@
+package shoutcast
+
+import "testing"
+
+func TestParseM3U(t *testing.T) {
+	body := []byte("#EXTM3U\n" +
+		"#EXTINF:-1,My Station\n" +
+		"http://example.com/stream\n")
+
+	entries := parseM3U(body)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].url != "http://example.com/stream" || entries[0].title != "My Station" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+}
+
+func TestParseM3UProgramDateTimeFallback(t *testing.T) {
+	body := []byte("#EXTM3U\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2020-01-01T00:00:00Z\n" +
+		"segment0.ts\n")
+
+	entries := parseM3U(body)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].title != "2020-01-01T00:00:00Z" {
+		t.Fatalf("title = %q, want program-date-time fallback", entries[0].title)
+	}
+}
+
+func TestParsePLS(t *testing.T) {
+	body := []byte("[playlist]\n" +
+		"File1=http://example.com/stream\n" +
+		"Title1=My Station\n" +
+		"Length1=-1\n" +
+		"NumberOfEntries=1\n")
+
+	entries := parsePLS(body)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if entries[0].url != "http://example.com/stream" || entries[0].title != "My Station" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+}
+
+func TestIsHLSMediaPlaylistRejectsPlainWebradioM3U8(t *testing.T) {
+	// A single-entry webradio .m3u8 redirect: has #EXTINF but none of the
+	// HLS-only tags, and must not be routed into the HLS path.
+	body := []byte("#EXTM3U\n#EXTINF:-1,My Station\nhttp://example.com/stream\n")
+	if isHLSMediaPlaylist("audio/x-mpegurl", "http://example.com/station.m3u8", body) {
+		t.Fatal("plain webradio .m3u8 with #EXTINF misidentified as an HLS media playlist")
+	}
+}
+
+func TestIsHLSMediaPlaylistAcceptsRealHLS(t *testing.T) {
+	body := []byte("#EXTM3U\n" +
+		"#EXT-X-VERSION:3\n" +
+		"#EXT-X-TARGETDURATION:10\n" +
+		"#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXTINF:10.0,\n" +
+		"segment0.ts\n")
+	if !isHLSMediaPlaylist("application/vnd.apple.mpegurl", "http://example.com/media.m3u8", body) {
+		t.Fatal("real HLS media playlist not recognized")
+	}
+}
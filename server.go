@@ -0,0 +1,285 @@
+package shoutcast
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// clientBacklog is how many pending audio chunks a listener may have
+// queued before it is considered a slow consumer and disconnected.
+const clientBacklog = 32
+
+// Mount represents a single ICY/Shoutcast broadcast point. Audio written to
+// a Mount is fanned out to every connected HTTP listener, interleaved with
+// ICY metadata blocks the same way a real Shoutcast/Icecast server does.
+type Mount struct {
+	// The name of the server
+	Name string
+
+	// What category the server falls under
+	Genre string
+
+	// The description of the stream
+	Description string
+
+	// Homepage of the server
+	URL string
+
+	// Bitrate the mount is encoded at
+	Bitrate int
+
+	// Amount of bytes between metadata blocks sent to clients
+	MetaInt int
+
+	mu        sync.Mutex
+	buf       *ringBuffer
+	metaBlock []byte
+	listeners map[*listener]struct{}
+}
+
+// NewMount creates a Mount ready to accept audio via Write and listeners via
+// ServeHTTP. backlog is the number of recent audio bytes retained so late
+// joiners get a low-latency start instead of silence.
+func NewMount(name, genre, description, url string, bitrate, metaint, backlog int) *Mount {
+	return &Mount{
+		Name:        name,
+		Genre:       genre,
+		Description: description,
+		URL:         url,
+		Bitrate:     bitrate,
+		MetaInt:     metaint,
+		buf:         newRingBuffer(backlog),
+		metaBlock:   encodeMetadataBlock(""),
+		listeners:   make(map[*listener]struct{}),
+	}
+}
+
+// Write appends audio to the mount and broadcasts it to all connected
+// listeners. It implements io.Writer so a Mount can sit directly behind an
+// encoder, or behind an existing Stream to relay a pulled stream back out.
+func (m *Mount) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	m.buf.Write(p)
+	for l := range m.listeners {
+		if !l.send(p) {
+			delete(m.listeners, l)
+			close(l.data)
+		}
+	}
+	m.mu.Unlock()
+	return len(p), nil
+}
+
+// SetMetadata pushes a new StreamTitle out to all currently connected
+// listeners. The change is applied atomically: every listener switches to
+// the new metadata block on its very next interleave point.
+func (m *Mount) SetMetadata(title string) {
+	block := encodeMetadataBlock(fmt.Sprintf("StreamTitle='%s';", title))
+	m.mu.Lock()
+	m.metaBlock = block
+	m.mu.Unlock()
+}
+
+// ServeHTTP negotiates Icy-MetaData with the client, writes the standard
+// icy-* response headers, and streams audio to the client for as long as
+// the connection stays open.
+func (m *Mount) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// A Mount with MetaInt <= 0 has no interleave point to offer, so ICY
+	// metadata is disabled regardless of what the client asked for.
+	icy := r.Header.Get("Icy-MetaData") == "1" && m.MetaInt > 0
+
+	h := w.Header()
+	h.Set("icy-name", m.Name)
+	h.Set("icy-genre", m.Genre)
+	h.Set("icy-url", m.URL)
+	h.Set("icy-description", m.Description)
+	h.Set("icy-br", strconv.Itoa(m.Bitrate))
+	if icy {
+		h.Set("icy-metaint", strconv.Itoa(m.MetaInt))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	l := newListener()
+	m.mu.Lock()
+	backlog := m.buf.Bytes()
+	meta := m.metaBlock
+	m.listeners[l] = struct{}{}
+	m.mu.Unlock()
+
+	log.Print("[INFO] Listener connected to mount ", m.Name)
+	defer func() {
+		m.mu.Lock()
+		delete(m.listeners, l)
+		m.mu.Unlock()
+		log.Print("[INFO] Listener disconnected from mount ", m.Name)
+	}()
+
+	pos := 0
+	writeAudio := func(p []byte) error {
+		for len(p) > 0 {
+			if !icy {
+				if _, err := w.Write(p); err != nil {
+					return err
+				}
+				break
+			}
+
+			remaining := m.MetaInt - pos
+			if remaining > len(p) {
+				if _, err := w.Write(p); err != nil {
+					return err
+				}
+				pos += len(p)
+				break
+			}
+
+			if _, err := w.Write(p[:remaining]); err != nil {
+				return err
+			}
+			m.mu.Lock()
+			meta = m.metaBlock
+			m.mu.Unlock()
+			if _, err := w.Write(meta); err != nil {
+				return err
+			}
+			p = p[remaining:]
+			pos = 0
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	if err := writeAudio(backlog); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case chunk, ok := <-l.data:
+			if !ok {
+				return
+			}
+			if err := writeAudio(chunk); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// listener is one connected client's delivery queue. Audio is handed off
+// via a buffered channel so a single slow client can never block the
+// broadcaster or other listeners.
+type listener struct {
+	data chan []byte
+}
+
+func newListener() *listener {
+	return &listener{data: make(chan []byte, clientBacklog)}
+}
+
+// send queues a chunk of audio for delivery, returning false if the
+// listener's backlog is full. The caller is expected to drop and close a
+// listener that returns false rather than blocking on it.
+func (l *listener) send(p []byte) bool {
+	cp := make([]byte, len(p))
+	copy(cp, p)
+	select {
+	case l.data <- cp:
+		return true
+	default:
+		return false
+	}
+}
+
+// encodeMetadataBlock frames s as an ICY metadata block: a single length
+// byte (the payload length in multiples of 16, rounded up) followed by the
+// payload padded with zero bytes to that length.
+func encodeMetadataBlock(s string) []byte {
+	if s == "" {
+		return []byte{0}
+	}
+	payload := []byte(s)
+	blocks := (len(payload) + 15) / 16
+	out := make([]byte, 1+blocks*16)
+	out[0] = byte(blocks)
+	copy(out[1:], payload)
+	return out
+}
+
+// ringBuffer is a fixed-size circular byte buffer used to retain recent
+// audio so newly connected listeners can start playback immediately
+// instead of waiting in silence for the next broadcast chunk.
+type ringBuffer struct {
+	buf  []byte
+	size int
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size), size: size}
+}
+
+// Write copies p into the buffer, overwriting the oldest data once the
+// buffer is full.
+func (r *ringBuffer) Write(p []byte) {
+	if r.size == 0 {
+		return
+	}
+	if len(p) >= r.size {
+		copy(r.buf, p[len(p)-r.size:])
+		r.pos = 0
+		r.full = true
+		return
+	}
+	n := copy(r.buf[r.pos:], p)
+	if n < len(p) {
+		copy(r.buf, p[n:])
+	}
+	if r.pos+len(p) >= r.size {
+		r.full = true
+	}
+	r.pos = (r.pos + len(p)) % r.size
+}
+
+// Bytes returns the buffered audio in write order, oldest first.
+func (r *ringBuffer) Bytes() []byte {
+	if !r.full {
+		return append([]byte(nil), r.buf[:r.pos]...)
+	}
+	out := make([]byte, r.size)
+	copy(out, r.buf[r.pos:])
+	copy(out[r.size-r.pos:], r.buf[:r.pos])
+	return out
+}
+
+// Server multiplexes one or more Mounts under HTTP paths, mirroring how a
+// real Shoutcast/Icecast instance serves several stations from one process.
+type Server struct {
+	mux *http.ServeMux
+}
+
+// NewServer creates an empty Server.
+func NewServer() *Server {
+	return &Server{mux: http.NewServeMux()}
+}
+
+// Handle registers a Mount so it is served at path, e.g. "/stream".
+func (s *Server) Handle(path string, m *Mount) {
+	s.mux.Handle(path, m)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,174 @@
+package shoutcast
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRingBufferWraparound(t *testing.T) {
+	r := newRingBuffer(8)
+
+	r.Write([]byte("abcd"))
+	if got := string(r.Bytes()); got != "abcd" {
+		t.Fatalf("Bytes() = %q, want %q", got, "abcd")
+	}
+
+	// Wraps around the end of the buffer.
+	r.Write([]byte("efghij"))
+	if got := string(r.Bytes()); got != "cdefghij" {
+		t.Fatalf("Bytes() after wrap = %q, want %q", got, "cdefghij")
+	}
+
+	// A single write larger than the buffer keeps only its tail.
+	r.Write([]byte("0123456789"))
+	if got := string(r.Bytes()); got != "23456789" {
+		t.Fatalf("Bytes() after oversized write = %q, want %q", got, "23456789")
+	}
+}
+
+func TestEncodeMetadataBlock(t *testing.T) {
+	empty := encodeMetadataBlock("")
+	if len(empty) != 1 || empty[0] != 0 {
+		t.Fatalf("encodeMetadataBlock(\"\") = %v, want [0]", empty)
+	}
+
+	block := encodeMetadataBlock("StreamTitle='x';")
+	wantBlocks := (len("StreamTitle='x';") + 15) / 16
+	if int(block[0]) != wantBlocks {
+		t.Fatalf("length byte = %d, want %d", block[0], wantBlocks)
+	}
+	if len(block) != 1+wantBlocks*16 {
+		t.Fatalf("block length = %d, want %d", len(block), 1+wantBlocks*16)
+	}
+	if string(block[1:1+len("StreamTitle='x';")]) != "StreamTitle='x';" {
+		t.Fatalf("block payload = %q", block[1:])
+	}
+	for _, b := range block[1+len("StreamTitle='x';"):] {
+		if b != 0 {
+			t.Fatalf("expected zero padding, got %v", block)
+		}
+	}
+}
+
+// waitForListenerCount polls m's listener count until it matches want or the
+// deadline passes, so tests don't race the goroutine running ServeHTTP.
+func waitForListenerCount(t *testing.T, m *Mount, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		m.mu.Lock()
+		got := len(m.listeners)
+		m.mu.Unlock()
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("listener count = %d, want %d", got, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMountServeHTTPInterleavesMetadataAtOffset(t *testing.T) {
+	m := NewMount("Test Station", "Test", "desc", "http://example.com", 128, 4, 0)
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("icy-metaint"); got != "4" {
+		t.Fatalf("icy-metaint = %q, want %q", got, "4")
+	}
+
+	waitForListenerCount(t, m, 1)
+
+	if _, err := m.Write([]byte("AAAA")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := m.Write([]byte("BBBB")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// 4 bytes of audio, a 1-byte empty metadata block (no SetMetadata call
+	// yet), then 4 more bytes of audio.
+	got := make([]byte, 9)
+	if _, err := io.ReadFull(resp.Body, got); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	if string(got[:4]) != "AAAA" {
+		t.Fatalf("audio before metadata = %q, want %q", got[:4], "AAAA")
+	}
+	if got[4] != 0 {
+		t.Fatalf("metadata block byte = %d, want 0 (empty StreamTitle)", got[4])
+	}
+	if string(got[5:9]) != "BBBB" {
+		t.Fatalf("audio after metadata = %q, want %q", got[5:9], "BBBB")
+	}
+}
+
+// blockingResponseWriter simulates a client whose connection has stalled:
+// every Write blocks until the test releases it, so the goroutine serving
+// ServeHTTP never drains the listener's channel on its own.
+type blockingResponseWriter struct {
+	header  http.Header
+	release chan struct{}
+}
+
+func newBlockingResponseWriter() *blockingResponseWriter {
+	return &blockingResponseWriter{header: make(http.Header), release: make(chan struct{})}
+}
+
+func (b *blockingResponseWriter) Header() http.Header { return b.header }
+func (b *blockingResponseWriter) WriteHeader(int)     {}
+func (b *blockingResponseWriter) Write(p []byte) (int, error) {
+	<-b.release
+	return len(p), nil
+}
+func (b *blockingResponseWriter) Flush() {}
+
+func TestMountServeHTTPDropsSlowListener(t *testing.T) {
+	m := NewMount("Test Station", "Test", "desc", "http://example.com", 128, 0, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	bw := newBlockingResponseWriter()
+
+	done := make(chan struct{})
+	go func() {
+		m.ServeHTTP(bw, req)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		close(bw.release)
+		cancel()
+		<-done
+	})
+
+	waitForListenerCount(t, m, 1)
+
+	// The first write is picked up by ServeHTTP's delivery loop and blocks
+	// in Write; every write after that queues in the listener's channel
+	// (capacity clientBacklog) until it's full, at which point the
+	// broadcaster must drop the listener instead of blocking.
+	for i := 0; i < clientBacklog+8; i++ {
+		if _, err := m.Write([]byte("x")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	waitForListenerCount(t, m, 0)
+}
@@ -1,6 +1,7 @@
 package shoutcast
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -10,9 +11,37 @@ import (
 	"time"
 )
 
+// metadataProvider is implemented by underlying readers that carry their
+// own out-of-band metadata (e.g. an hlsReader synthesizing titles from
+// EXTINF tags) instead of the in-band ICY metaint framing.
+type metadataProvider interface {
+	nextMetadata() *Metadata
+}
+
 // MetadataCallbackFunc is the type of the function called when the stream metadata changes
 type MetadataCallbackFunc func(m *Metadata)
 
+// reconnectPolicy holds the exponential backoff bounds for the
+// WithReconnect option.
+type reconnectPolicy struct {
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// Option configures optional behavior of OpenContext.
+type Option func(*Stream)
+
+// WithReconnect makes a Stream automatically redial and resume when the
+// upstream connection drops, instead of surfacing the error to Read. It
+// backs off exponentially between attempts, starting at initial and
+// capping at max, and re-emits the last known metadata via
+// MetadataCallbackFunc once a reconnect succeeds.
+func WithReconnect(initial, max time.Duration) Option {
+	return func(s *Stream) {
+		s.reconnect = &reconnectPolicy{initialBackoff: initial, maxBackoff: max}
+	}
+}
+
 // Stream represents an open shoutcast stream.
 type Stream struct {
 	// The name of the server
@@ -44,13 +73,52 @@ type Stream struct {
 
 	// The underlying data stream
 	rc io.ReadCloser
+
+	// Context governing dial, headers and the stream's lifetime.
+	ctx context.Context
+
+	// The URL to redial on reconnect, empty for streams (like HLS) that
+	// can't simply be reopened by URL.
+	dialURL string
+
+	// Reconnect behavior, nil if WithReconnect wasn't used.
+	reconnect *reconnectPolicy
 }
 
-// Open establishes a connection to a remote server.
+// Open establishes a connection to a remote server. If url turns out to
+// point at a playlist (M3U, M3U8 or PLS) rather than a raw stream, it is
+// resolved transparently: Open follows it to the first playable entry it
+// contains.
 func Open(url string) (*Stream, error) {
-	log.Print("[INFO] Opening ", url)
+	return OpenContext(context.Background(), url)
+}
+
+// OpenContext establishes a connection to a remote server the same way
+// Open does, but honors ctx for the dial, the request headers, and the
+// full lifetime of the returned Stream: canceling ctx unblocks any
+// in-flight Read and causes future ones to fail. Options configure
+// optional behavior such as automatic reconnection.
+func OpenContext(ctx context.Context, url string, opts ...Option) (*Stream, error) {
+	s, err := openPlaylist(ctx, url, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ctx = ctx
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// fetchStream performs the actual HTTP GET used to open both streams and
+// playlists, with the headers and timeouts a Shoutcast/Icecast server
+// expects.
+func fetchStream(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Add("accept", "*/*")
 	req.Header.Add("user-agent", "iTunes/12.9.2 (Macintosh; OS X 10.14.3) AppleWebKit/606.4.5")
 	req.Header.Add("icy-metadata", "1")
@@ -61,7 +129,19 @@ func Open(url string) (*Stream, error) {
 	dialer := &net.Dialer{Timeout: 5 * time.Second}
 	transport := &http.Transport{Dial: dialer.Dial}
 	client := &http.Client{Transport: transport}
-	resp, err := client.Do(req)
+	return client.Do(req)
+}
+
+// openPlaylist opens url, following playlist redirects up to
+// maxPlaylistDepth levels deep before giving up.
+func openPlaylist(ctx context.Context, url string, depth int) (*Stream, error) {
+	if depth > maxPlaylistDepth {
+		return nil, fmt.Errorf("too many nested playlists resolving %s", url)
+	}
+
+	log.Print("[INFO] Opening ", url)
+
+	resp, err := fetchStream(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -70,16 +150,53 @@ func Open(url string) (*Stream, error) {
 		log.Print("[DEBUG] HTTP header ", k, ": ", v[0])
 	}
 
-	bitrate, err := strconv.Atoi(resp.Header.Get("icy-br"))
+	contentType := resp.Header.Get("Content-Type")
+	parse := playlistParserFor(contentType, url)
+	if parse == nil {
+		return newStream(resp, url)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse bitrate: %v", err)
+		return nil, fmt.Errorf("cannot read playlist %s: %v", url, err)
+	}
+
+	entries := parse(body)
+	if isHLSMediaPlaylist(contentType, url, body) {
+		return newHLSStream(ctx, url, entries)
+	}
+
+	for _, e := range entries {
+		target, err := resolvePlaylistURL(url, e.url)
+		if err != nil || !isPlayableURL(target) {
+			continue
+		}
+		return openPlaylist(ctx, target, depth+1)
 	}
 
+	return nil, fmt.Errorf("playlist %s contained no playable entries", url)
+}
+
+// newStream builds a Stream from a plain (non-playlist) ICY response.
+// dialURL is what reconnects, if enabled, will redial. A missing
+// icy-metaint together with an Ogg content type means metadata travels as
+// in-band Vorbis comment packets instead, so that case is handed off to
+// newOggStream rather than treated as an error.
+func newStream(resp *http.Response, dialURL string) (*Stream, error) {
 	metaint, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
 	if err != nil {
+		if isOggContentType(resp.Header.Get("Content-Type")) {
+			return newOggStream(resp, dialURL), nil
+		}
 		return nil, fmt.Errorf("cannot parse metaint: %v", err)
 	}
 
+	bitrate, err := strconv.Atoi(resp.Header.Get("icy-br"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse bitrate: %v", err)
+	}
+
 	s := &Stream{
 		Name:        resp.Header.Get("icy-name"),
 		Genre:       resp.Header.Get("icy-genre"),
@@ -90,41 +207,273 @@ func Open(url string) (*Stream, error) {
 		metadata:    nil,
 		pos:         0,
 		rc:          resp.Body,
+		ctx:         context.Background(),
+		dialURL:     dialURL,
 	}
 
 	return s, nil
 }
 
-// Read implements the standard Read interface
-func (s *Stream) Read(p []byte) (n int, err error) {
-	n, err = s.rc.Read(p)
+// newOggStream builds a Stream over an Ogg Vorbis/Opus/FLAC mount that
+// advertises no icy-metaint of its own. metaint is left at 0 and audio
+// passes straight through; oggReader parses Vorbis comment packets out of
+// the bitstream as it goes and surfaces them as metadataProvider does for
+// HLS.
+func newOggStream(resp *http.Response, dialURL string) *Stream {
+	bitrate, _ := strconv.Atoi(resp.Header.Get("icy-br"))
+
+	return &Stream{
+		Name:        resp.Header.Get("icy-name"),
+		Genre:       resp.Header.Get("icy-genre"),
+		Description: resp.Header.Get("icy-description"),
+		URL:         resp.Header.Get("icy-url"),
+		Bitrate:     bitrate,
+		metaint:     0,
+		rc:          newOggReader(resp.Body),
+		ctx:         context.Background(),
+		dialURL:     dialURL,
+	}
+}
 
-	if s.pos+n <= s.metaint {
-		s.pos = s.pos + n
+// newHLSStream builds a Stream that plays a continuous run of HLS media
+// segments as if they were one raw audio stream. Since segments carry no
+// in-band ICY metadata blocks, metaint is left at 0 and Read instead polls
+// the underlying hlsReader for synthesized metadata. HLS streams aren't
+// redialed by URL on reconnect; the hlsReader simply resumes at its next
+// segment.
+func newHLSStream(ctx context.Context, playlistURL string, entries []playlistEntry) (*Stream, error) {
+	return &Stream{
+		metaint: 0,
+		rc:      newHLSReader(ctx, playlistURL, entries),
+		ctx:     ctx,
+	}, nil
+}
+
+// Read implements the standard Read interface. ICY metadata blocks are
+// never copied into p; their StreamTitle, like whatever an underlying
+// metadataProvider supplies, is delivered solely through
+// MetadataCallbackFunc. An Ogg stream is passed through whole, its comment
+// packets included.
+func (s *Stream) Read(p []byte) (int, error) {
+	if s.metaint == 0 {
+		return s.readPassthrough(p)
+	}
+	return s.readWithMetadata(p)
+}
+
+// readPassthrough is used for streams (like HLS) with no in-band metadata
+// framing at all.
+func (s *Stream) readPassthrough(p []byte) (int, error) {
+	for {
+		n, err := s.rc.Read(p)
+		// A clean io.EOF is how most radio servers signal a dropped
+		// connection (restart, kick, keep-alive timeout), not just
+		// genuine end of stream, so it must be reconnectable too when
+		// WithReconnect is in effect. But Read is allowed to return a
+		// final chunk of data together with the error, and that data
+		// must reach the caller before we act on the error.
+		if err != nil && n == 0 && (err != io.EOF || s.reconnect != nil) {
+			if s.tryReconnect(err) {
+				continue
+			}
+			return n, err
+		}
+
+		if mp, ok := s.rc.(metadataProvider); ok {
+			if m := mp.nextMetadata(); m != nil && !m.Equals(s.metadata) {
+				s.metadata = m
+				if s.MetadataCallbackFunc != nil {
+					s.MetadataCallbackFunc(s.metadata)
+				}
+			}
+		}
+		if n > 0 && err == io.EOF {
+			// Don't hand the caller an error for bytes we successfully
+			// read; if the connection really did drop, the next Read
+			// will see it as (0, io.EOF).
+			return n, nil
+		}
 		return n, err
 	}
+}
+
+// readWithMetadata implements the ICY audio-then-metadata-block framing.
+// It never assumes a whole metadata block lands in a single underlying
+// Read the way the original implementation did; consumeMetadataBlock
+// buffers across as many reads as it takes.
+func (s *Stream) readWithMetadata(p []byte) (int, error) {
+	for {
+		if s.pos >= s.metaint {
+			if err := s.consumeMetadataBlock(); err != nil {
+				return 0, err
+			}
+			continue
+		}
 
-	// extract stream metadata
-	metadataStart := s.metaint - s.pos
-	metadataLength := int(p[metadataStart : metadataStart+1][0]) * 16
-	if metadataLength > 0 {
-		m := NewMetadata(p[metadataStart+1 : metadataStart+1+metadataLength])
-		if !m.Equals(s.metadata) {
-			s.metadata = m
-			if s.MetadataCallbackFunc != nil {
-				s.MetadataCallbackFunc(s.metadata)
+		max := s.metaint - s.pos
+		if max > len(p) {
+			max = len(p)
+		}
+
+		n, err := s.rc.Read(p[:max])
+		// As in readPassthrough, io.EOF must be reconnectable too when
+		// WithReconnect is in effect: it's the ordinary way a dropped
+		// connection is reported, not just legitimate stream end. But
+		// any bytes Read handed back alongside the error belong to the
+		// caller first; only reconnect once a Read truly comes back
+		// empty.
+		if err != nil && n == 0 && (err != io.EOF || s.reconnect != nil) {
+			if s.tryReconnect(err) {
+				continue
+			}
+			return n, err
+		}
+		s.pos += n
+		if n > 0 {
+			// A reader is allowed to return data alongside io.EOF in the
+			// same call; don't hand the caller an error for bytes we
+			// successfully read. If the connection really did drop, the
+			// next Read will see it as (0, io.EOF).
+			if err == io.EOF {
+				return n, nil
 			}
+			return n, err
 		}
+		if err == io.EOF {
+			return n, err
+		}
+	}
+}
+
+// consumeMetadataBlock reads and parses one full ICY metadata block: a
+// length byte followed by length*16 payload bytes, zero-padded. It blocks
+// until the whole block has arrived, however many underlying Read calls
+// that takes.
+func (s *Stream) consumeMetadataBlock() error {
+	lengthByte := make([]byte, 1)
+	if err := s.fillFromStream(lengthByte); err != nil {
+		return err
+	}
+
+	s.pos = 0
+	metadataLength := int(lengthByte[0]) * 16
+	if metadataLength == 0 {
+		return nil
 	}
 
-	// roll over position + metadata block
-	s.pos = ((s.pos + n) - s.metaint) - metadataLength - 1
+	payload := make([]byte, metadataLength)
+	if err := s.fillFromStream(payload); err != nil {
+		return err
+	}
 
-	// shift buffer data to account for metadata block
-	copy(p[metadataStart:], p[metadataStart+1+metadataLength:])
-	n = n - 1 - metadataLength
+	m := NewMetadata(payload)
+	if !m.Equals(s.metadata) {
+		s.metadata = m
+		if s.MetadataCallbackFunc != nil {
+			s.MetadataCallbackFunc(s.metadata)
+		}
+	}
+	return nil
+}
 
-	return n, err
+// fillFromStream reads into buf until it is completely full, the same way
+// io.ReadFull would, except that it also reconnects (when enabled)
+// instead of giving up on the first dropped connection.
+func (s *Stream) fillFromStream(buf []byte) error {
+	for filled := 0; filled < len(buf); {
+		n, err := s.rc.Read(buf[filled:])
+		filled += n
+		if filled >= len(buf) {
+			return nil
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			if s.tryReconnect(err) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// tryReconnect redials dialURL with exponential backoff when reconnect is
+// enabled, swapping in the new connection while preserving the Stream's
+// identity. It returns false (leaving cause for the caller to surface) if
+// reconnection is disabled, unsupported for this stream, or ctx is done.
+func (s *Stream) tryReconnect(cause error) bool {
+	if s.reconnect == nil || s.dialURL == "" {
+		return false
+	}
+
+	log.Print("[WARN] stream dropped (", cause, "), reconnecting to ", s.dialURL)
+	s.rc.Close()
+
+	backoff := s.reconnect.initialBackoff
+	for {
+		select {
+		case <-s.ctx.Done():
+			return false
+		default:
+		}
+
+		resp, err := fetchStream(s.ctx, s.dialURL)
+		if err == nil {
+			reopened, rerr := newStream(resp, s.dialURL)
+			if rerr == nil {
+				s.rc = reopened.rc
+				s.metaint = reopened.metaint
+				s.pos = 0
+				s.Name = reopened.Name
+				s.Genre = reopened.Genre
+				s.Description = reopened.Description
+				s.URL = reopened.URL
+				s.Bitrate = reopened.Bitrate
+
+				log.Print("[INFO] Reconnected to ", s.dialURL)
+				if s.MetadataCallbackFunc != nil && s.metadata != nil {
+					s.MetadataCallbackFunc(s.metadata)
+				}
+				return true
+			}
+			resp.Body.Close()
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > s.reconnect.maxBackoff {
+			backoff = s.reconnect.maxBackoff
+		}
+	}
+}
+
+// Pipe copies pure audio bytes to audio until the stream ends or an error
+// occurs, delivering metadata only through MetadataCallbackFunc. It exists
+// for decoders that shouldn't have to be Readers of a stream that can, in
+// principle, reshuffle its own buffer.
+func (s *Stream) Pipe(audio io.Writer) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.Read(buf)
+		if n > 0 {
+			if _, werr := audio.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
 }
 
 // Close closes the stream
@@ -0,0 +1,109 @@
+package shoutcast
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// chunkedReader dribbles out data a fixed number of bytes at a time,
+// regardless of how much room the caller's buffer has, so tests can force
+// a metadata block to straddle several underlying Read calls.
+type chunkedReader struct {
+	data []byte
+	pos  int
+	step int
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.pos >= len(c.data) {
+		return 0, io.EOF
+	}
+	n := c.step
+	if n > len(p) {
+		n = len(p)
+	}
+	if c.pos+n > len(c.data) {
+		n = len(c.data) - c.pos
+	}
+	copy(p, c.data[c.pos:c.pos+n])
+	c.pos += n
+	return n, nil
+}
+
+func (c *chunkedReader) Close() error { return nil }
+
+func TestReadReassemblesMetadataBlockAcrossReads(t *testing.T) {
+	audio1 := []byte("AAAA")
+	metaBlock := encodeMetadataBlock("StreamTitle='Hello';")
+	audio2 := []byte("BBBB")
+
+	raw := append(append(append([]byte{}, audio1...), metaBlock...), audio2...)
+
+	s := &Stream{
+		metaint: len(audio1),
+		rc:      &chunkedReader{data: raw, step: 3},
+		ctx:     context.Background(),
+	}
+
+	var got []byte
+	buf := make([]byte, 16)
+	for len(got) < len(audio1)+len(audio2) {
+		n, err := s.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil && err != io.EOF {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+
+	want := append(append([]byte{}, audio1...), audio2...)
+	if string(got) != string(want) {
+		t.Fatalf("got audio %q, want %q (metadata block leaked into audio)", got, want)
+	}
+	if s.metadata == nil || s.metadata.StreamTitle != "Hello" {
+		t.Fatalf("metadata = %+v, want StreamTitle=Hello", s.metadata)
+	}
+}
+
+// eofReader simulates an upstream that has already dropped the
+// connection: every Read reports a clean io.EOF, the ordinary way an
+// HTTP/1.x radio stream signals the server closed the connection.
+type eofReader struct{}
+
+func (eofReader) Read(p []byte) (int, error) { return 0, io.EOF }
+func (eofReader) Close() error               { return nil }
+
+func TestReadReconnectsOnEOF(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("icy-br", "128")
+		w.Header().Set("icy-metaint", "4")
+		w.Write([]byte("DATA"))
+	}))
+	defer server.Close()
+
+	s := &Stream{
+		metaint:   4,
+		rc:        eofReader{},
+		ctx:       context.Background(),
+		dialURL:   server.URL,
+		reconnect: &reconnectPolicy{initialBackoff: time.Millisecond, maxBackoff: time.Millisecond},
+	}
+
+	buf := make([]byte, 16)
+	n, err := s.Read(buf)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if string(buf[:n]) != "DATA" {
+		t.Fatalf("got %q, want %q", buf[:n], "DATA")
+	}
+	if atomic.LoadInt32(&hits) == 0 {
+		t.Fatal("a dropped connection (clean io.EOF) with WithReconnect configured never redialed the backing server")
+	}
+}